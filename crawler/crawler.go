@@ -12,11 +12,11 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	mapset "github.com/deckarep/golang-set"
 	"github.com/gocolly/colly/v2"
-	"github.com/gocolly/colly/v2/queue"
 )
 
 // the following domains are excluded from crawling & indexing, typically because they have a lot of microblog pages
@@ -134,35 +134,50 @@ func cleanText(s string) string {
 	return s
 }
 
-func handleIndexing(c *colly.Collector, previewQueries []string, heuristics []string, linkDepths map[string]int) {
+func handleIndexing(c *colly.Collector, indexer Indexer, previewQueries []string, heuristics []string, linkDepths map[string]int) {
+	// declaredLang is written by the html[lang] handler and read by the
+	// body handler below, both of which colly invokes concurrently across
+	// pages (queue threads, per-domain Parallelism), so access is guarded
+	// by a mutex.
+	var declaredLangMu sync.Mutex
+	declaredLang := make(map[string]string)
+
 	c.OnHTML("meta[name=\"keywords\"]", func(e *colly.HTMLElement) {
-		fmt.Println("keywords", cleanText(e.Attr("content")), e.Request.URL, linkDepths[e.Request.URL.String()])
+		emit(indexer, "keywords", cleanText(e.Attr("content")), e.Request.URL.String(), linkDepths[e.Request.URL.String()])
 	})
 
 	c.OnHTML("meta[name=\"description\"]", func(e *colly.HTMLElement) {
 		desc := cleanText(e.Attr("content"))
 		if len(desc) > 0 && len(desc) < 1500 {
-			fmt.Println("desc", desc, e.Request.URL, linkDepths[e.Request.URL.String()])
+			emit(indexer, "desc", desc, e.Request.URL.String(), linkDepths[e.Request.URL.String()])
 		}
 	})
 
 	c.OnHTML("meta[property=\"og:description\"]", func(e *colly.HTMLElement) {
 		ogDesc := cleanText(e.Attr("content"))
 		if len(ogDesc) > 0 && len(ogDesc) < 1500 {
-			fmt.Println("og-desc", ogDesc, e.Request.URL, linkDepths[e.Request.URL.String()])
+			emit(indexer, "og-desc", ogDesc, e.Request.URL.String(), linkDepths[e.Request.URL.String()])
 		}
 	})
 
 	c.OnHTML("html[lang]", func(e *colly.HTMLElement) {
 		lang := cleanText(e.Attr("lang"))
 		if len(lang) > 0 && len(lang) < 100 {
-			fmt.Println("lang", lang, e.Request.URL, linkDepths[e.Request.URL.String()])
+			declaredLangMu.Lock()
+			declaredLang[e.Request.URL.String()] = lang
+			declaredLangMu.Unlock()
+			emit(indexer, "lang", lang, e.Request.URL.String(), linkDepths[e.Request.URL.String()])
 		}
 	})
 
 	// get page title
 	c.OnHTML("title", func(e *colly.HTMLElement) {
-		fmt.Println("title", cleanText(e.Text), e.Request.URL, linkDepths[e.Request.URL.String()])
+		emit(indexer, "title", cleanText(e.Text), e.Request.URL.String(), linkDepths[e.Request.URL.String()])
+	})
+
+	// site metadata lets a downstream search layer implement site:/-site: query operators
+	c.OnHTML("html", func(e *colly.HTMLElement) {
+		emit(indexer, "site", registeredDomain(e.Request.URL.Hostname()), e.Request.URL.String(), linkDepths[e.Request.URL.String()])
 	})
 
 	c.OnHTML("body", func(e *colly.HTMLElement) {
@@ -175,7 +190,7 @@ func handleIndexing(c *colly.Collector, previewQueries []string, heuristics []st
 				paragraph := cleanText(element_text)
 				if len(paragraph) < 1500 && len(paragraph) > 20 {
 					if !util.Contains(heuristics, strings.ToLower(paragraph)) {
-						fmt.Println("para", paragraph, e.Request.URL, linkDepths[e.Request.URL.String()])
+						emit(indexer, "para", paragraph, e.Request.URL.String(), linkDepths[e.Request.URL.String()])
 						break QueryLoop
 					}
 				}
@@ -183,24 +198,47 @@ func handleIndexing(c *colly.Collector, previewQueries []string, heuristics []st
 		}
 		paragraph := cleanText(e.DOM.Find("p").First().Text())
 		if len(paragraph) < 1500 && len(paragraph) > 0 {
-			fmt.Println("para-just-p", paragraph, e.Request.URL, linkDepths[e.Request.URL.String()])
+			emit(indexer, "para-just-p", paragraph, e.Request.URL.String(), linkDepths[e.Request.URL.String()])
 		}
 
 		// get all relevant page headings
-		collectHeadingText("h1", e, linkDepths)
-		collectHeadingText("h2", e, linkDepths)
-		collectHeadingText("h3", e, linkDepths)
+		collectHeadingText("h1", e, indexer, linkDepths)
+		collectHeadingText("h2", e, indexer, linkDepths)
+		collectHeadingText("h3", e, indexer, linkDepths)
+
+		// html[lang] is frequently missing or a default "en"/"und" that doesn't
+		// reflect the actual page, so fall back to detecting it from the text
+		// we've already extracted for indexing.
+		declaredLangMu.Lock()
+		generic := isGenericLang(declaredLang[e.Request.URL.String()])
+		declaredLangMu.Unlock()
+		if generic {
+			title := cleanText(e.DOM.Find("title").Text())
+			if detected := detectLanguage(title + " " + paragraph); detected != "" {
+				emit(indexer, "lang-detected", detected, e.Request.URL.String(), linkDepths[e.Request.URL.String()])
+			}
+		}
 	})
 }
 
-func collectHeadingText(heading string, e *colly.HTMLElement, linkDepths map[string]int) {
+func collectHeadingText(heading string, e *colly.HTMLElement, indexer Indexer, linkDepths map[string]int) {
 	for _, headingText := range e.ChildTexts(heading) {
 		if len(headingText) < 500 {
-			fmt.Println(heading, cleanText(headingText), e.Request.URL, linkDepths[e.Request.URL.String()])
+			emit(indexer, heading, cleanText(headingText), e.Request.URL.String(), linkDepths[e.Request.URL.String()])
 		}
 	}
 }
 
+// emit wraps Indexer.Emit so callbacks can stay one-liners; a write failure
+// is logged rather than aborting the crawl, matching the old fire-and-forget
+// fmt.Println behavior.
+func emit(indexer Indexer, kind, value, url string, depth int) {
+	err := indexer.Emit(IndexRecord{Kind: kind, Value: value, URL: url, Depth: depth})
+	if err != nil {
+		log.Println("failed to emit index record:", err)
+	}
+}
+
 func SetupDefaultProxy(config types.Config) error {
 	// no proxy configured, go back
 	if config.General.Proxy == "" {
@@ -221,7 +259,26 @@ func SetupDefaultProxy(config types.Config) error {
 	return nil
 }
 
+// Precrawl seeds the crawl's webring list from whatever source format the
+// operator configured, rather than assuming the bespoke JSON cluster shape.
 func Precrawl(config types.Config) {
+	switch config.General.WebringFormat {
+	case "", "json":
+		precrawlJSON(config)
+	case "html":
+		precrawlHTML(config)
+	case "opml":
+		precrawlOPML(config)
+	case "list":
+		precrawlList(config)
+	default:
+		log.Fatal("unknown webring format: ", config.General.WebringFormat)
+	}
+}
+
+// precrawlJSON is the original webring precrawl, walking the bespoke
+// types.Cluster (Location/Hyphae/Spores) shape one hop at a time.
+func precrawlJSON(config types.Config) {
 	myClient := &http.Client{Timeout: 10 * time.Second}
 	// setup proxy
 	err := SetupDefaultProxy(config)
@@ -236,8 +293,17 @@ func Precrawl(config types.Config) {
 	}
 
 	depthCounter := 0
-	checked := mapset.NewSet()
-	allHyphae := mapset.NewSet()
+
+	var checked, allHyphae *PersistentSet
+	if config.Crawler.PersistentQueue {
+		checked, err = LoadPersistentSet(config.Crawler.StatePath + ".checked.json")
+		util.Check(err)
+		allHyphae, err = LoadPersistentSet(config.Crawler.StatePath + ".hyphae.json")
+		util.Check(err)
+	} else {
+		checked = &PersistentSet{set: mapset.NewSet()}
+		allHyphae = &PersistentSet{set: mapset.NewSet()}
+	}
 	allSites := mapset.NewSet()
 
 	precrawled := false
@@ -255,17 +321,22 @@ func Precrawl(config types.Config) {
 
 		defer res.Body.Close()
 
-		checked.Add(types.Hypha{Url: cluster.Location, Depth: cluster.Depth})
+		checked.set.Add(types.Hypha{Url: cluster.Location, Depth: cluster.Depth})
 
 		for _, v := range cluster.Hyphae {
-			allHyphae.Add(types.Hypha{Url: v, Depth: cluster.Depth + 1})
+			allHyphae.set.Add(types.Hypha{Url: v, Depth: cluster.Depth + 1})
 		}
 
 		for _, v := range cluster.Spores {
 			allSites.Add(types.Site{Url: v, Depth: cluster.Depth})
 		}
 
-		diff := allHyphae.Difference(checked)
+		if config.Crawler.PersistentQueue {
+			util.Check(checked.Save())
+			util.Check(allHyphae.Save())
+		}
+
+		diff := allHyphae.set.Difference(checked.set)
 
 		if diff.Cardinality() == 0 {
 			precrawled = true
@@ -294,18 +365,24 @@ func Precrawl(config types.Config) {
 	BANNED := getBannedDomains(config.Crawler.BannedDomains)
 	for _, item := range allSites.ToSlice() {
 		h := item.(types.Site)
-		link := getLink(fmt.Sprintf("%v", h.Url))
-		u, err := url.Parse(link)
-		// invalid link
-		if err != nil {
-			continue
-		}
-		domain := u.Hostname()
-		if find(BANNED, domain) {
-			continue
-		}
-		fmt.Println(link, h.Depth)
+		printWebringLink(fmt.Sprintf("%v", h.Url), h.Depth, BANNED)
+	}
+}
+
+// printWebringLink cleans and validates a candidate webring URL and, unless
+// it's on a banned domain, prints it as "link depth" — the format
+// getWebringLinks expects regardless of which source format produced it.
+func printWebringLink(rawLink string, depth int, banned []string) {
+	link := getLink(rawLink)
+	u, err := url.Parse(link)
+	// invalid link
+	if err != nil {
+		return
 	}
+	if find(banned, u.Hostname()) {
+		return
+	}
+	fmt.Println(link, depth)
 }
 
 func Crawl(config types.Config) {
@@ -330,10 +407,16 @@ func Crawl(config types.Config) {
 		c.SetProxy(config.General.Proxy)
 	}
 
-	q, _ := queue.New(
-		5, /* threads */
-		&queue.InMemoryQueueStorage{MaxSize: 100000},
-	)
+	q, err := newQueue(config.Crawler.PersistentQueue, config.Crawler.StatePath+".queue.db", 5 /* threads */)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	indexer, err := NewIndexer(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer indexer.Close()
 
 	for i, link := range links {
 		q.AddURL(link)
@@ -341,20 +424,51 @@ func Crawl(config types.Config) {
 	}
 
 	c.UserAgent = "moldy"
-	c.AllowedDomains = domains
 	c.AllowURLRevisit = false
 	c.DisallowedDomains = getBannedDomains(config.Crawler.BannedDomains)
 
-	delay, _ := time.ParseDuration("1000ms")
-	c.Limit(&colly.LimitRule{DomainGlob: "*", Delay: delay, Parallelism: 3})
+	defaultDelay := time.Duration(config.Crawler.DefaultDelayMs) * time.Millisecond
+	if defaultDelay == 0 {
+		defaultDelay = 1000 * time.Millisecond
+	}
+	c.Limit(&colly.LimitRule{DomainGlob: "*", Delay: defaultDelay, Parallelism: 3})
+
+	// setupRobots enforces robots.txt itself (via robotstxt.TestAgent), so
+	// colly's own robots support stays off to avoid fetching it twice per host.
+	c.IgnoreRobotsTxt = true
+	if config.Crawler.RespectRobots {
+		setupRobots(c, q, newRobotsCache(), defaultDelay, config.Crawler.UseSitemaps)
+	}
+
+	maxBodyBytes := config.Crawler.MaxBodyBytes
+	if maxBodyBytes == 0 {
+		maxBodyBytes = 1024 * 1024 // 1 MiB
+	}
+	limiter := newHostLimiter(config.Crawler.MaxHostsPerDomain, config.Crawler.MaxPagesPerHost)
+	setupHostLimits(c, limiter, maxBodyBytes)
 
 	boringDomains := getBoringDomains(config.Crawler.BoringDomains)
 	boringWords := getBoringWords(config.Crawler.BoringWords)
 	previewQueries := getPreviewQueries(config.Crawler.PreviewQueries)
 	heuristics := getAboutHeuristics(config.Data.Heuristics)
 
+	crossedDomains := newCrossDomainTracker()
+
+	// Enforce the domain whitelist ourselves instead of via colly's
+	// c.AllowedDomains: ArchiveMode needs to grow that whitelist at
+	// runtime as asset links cross domains, and colly's own isDomainAllowed
+	// reads c.AllowedDomains unguarded from its own internal goroutines, so
+	// mutating it from a callback would race no matter how we guarded the
+	// write. domains is fixed for the whole crawl and crossedDomains is
+	// backed by a thread-safe mapset.Set, so this check is race-free.
+	c.OnRequest(func(r *colly.Request) {
+		if !domainAllowed(domains, crossedDomains, r.URL.Hostname()) {
+			r.Abort()
+		}
+	})
+
 	c.OnError(func(r *colly.Response, err error) {
-		fmt.Println("Request URL:", r.Request.URL, "failed with response:", r, "\nError:", err)
+		log.Println("Request URL:", r.Request.URL, "failed with response:", r, "\nError:", err)
 	})
 
 	// on every a element which has an href attribute, call callback
@@ -378,13 +492,17 @@ func Crawl(config types.Config) {
 		outgoingDomain := u.Hostname()
 		currentDomain := e.Request.URL.Hostname()
 
+		if !limiter.allowHost(outgoingDomain) {
+			return
+		}
+
 		// log which site links to what
 		if !util.Contains(boringWords, link) && !util.Contains(boringDomains, link) {
 			if !find(domains, outgoingDomain) {
-				fmt.Println("non-webring-link", link, e.Request.URL, linkDepths[e.Request.URL.String()])
+				emit(indexer, "non-webring-link", link, e.Request.URL.String(), linkDepths[e.Request.URL.String()])
 				// solidarity! someone in the webring linked to someone else in it
 			} else if outgoingDomain != currentDomain && outgoingDomain != initialDomain && currentDomain != initialDomain {
-				fmt.Println("webring-link", link, e.Request.URL, linkDepths[e.Request.URL.String()])
+				emit(indexer, "webring-link", link, e.Request.URL.String(), linkDepths[e.Request.URL.String()])
 			}
 		}
 
@@ -401,15 +519,17 @@ func Crawl(config types.Config) {
 		if pathsite != "" {
 			// make sure we're only crawling descendents of the original path
 			if strings.HasPrefix(link, pathsite) {
-				q.AddURL(link)
+				queueLink(q, link, Primary, domains, currentDomain, outgoingDomain, config.Crawler.ArchiveMode, crossedDomains)
 			}
 		} else {
 			// visits links from AllowedDomains
-			q.AddURL(link)
+			queueLink(q, link, Primary, domains, currentDomain, outgoingDomain, config.Crawler.ArchiveMode, crossedDomains)
 		}
 	})
 
-	handleIndexing(c, previewQueries, heuristics, linkDepths)
+	handleAssetLinks(c, q, domains, config.Crawler.ArchiveMode, crossedDomains)
+
+	handleIndexing(c, indexer, previewQueries, heuristics, linkDepths)
 
 	// start scraping
 	q.Run(c)
@@ -0,0 +1,41 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gocolly/colly/v2"
+)
+
+type noopIndexer struct{}
+
+func (noopIndexer) Emit(IndexRecord) error { return nil }
+func (noopIndexer) Close() error           { return nil }
+
+// declaredLang is written by the html[lang] handler and read by the body
+// handler, both registered by handleIndexing; colly runs OnHTML callbacks
+// concurrently across pages, so this must be race-free under -race.
+func TestHandleIndexingDeclaredLangConcurrentAccess(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html lang="en"><head><title>Test page</title></head>`+
+			`<body><p>hello world, this is a test paragraph long enough to be indexed</p></body></html>`)
+	}))
+	defer srv.Close()
+
+	c := colly.NewCollector(colly.Async(true))
+	if err := c.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: 8}); err != nil {
+		t.Fatalf("Limit: %v", err)
+	}
+
+	linkDepths := make(map[string]int)
+	handleIndexing(c, noopIndexer{}, []string{"p"}, nil, linkDepths)
+
+	for i := 0; i < 20; i++ {
+		if err := c.Visit(fmt.Sprintf("%s/page%d", srv.URL, i)); err != nil {
+			t.Fatalf("Visit: %v", err)
+		}
+	}
+	c.Wait()
+}
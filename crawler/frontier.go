@@ -0,0 +1,135 @@
+package crawler
+
+import (
+	"encoding/json"
+	"lieu/types"
+	"os"
+
+	mapset "github.com/deckarep/golang-set"
+	"github.com/gocolly/colly/v2/queue"
+	bolt "go.etcd.io/bbolt"
+)
+
+var queueBucket = []byte("queue")
+
+// BoltQueueStorage persists colly's request queue to a BoltDB file, so
+// Crawl can be killed and resumed without losing the frontier. It satisfies
+// colly's queue.Storage interface.
+type BoltQueueStorage struct {
+	db *bolt.DB
+}
+
+func NewBoltQueueStorage(path string) (*BoltQueueStorage, error) {
+	db, err := bolt.Open(path, 0644, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(queueBucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &BoltQueueStorage{db: db}, nil
+}
+
+func (s *BoltQueueStorage) Init() error {
+	return nil
+}
+
+func (s *BoltQueueStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltQueueStorage) AddRequest(r []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(queueBucket)
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		return b.Put(itob(id), r)
+	})
+}
+
+func (s *BoltQueueStorage) GetRequest() ([]byte, error) {
+	var request []byte
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(queueBucket)
+		c := b.Cursor()
+		k, v := c.First()
+		if k == nil {
+			return nil
+		}
+		request = append([]byte{}, v...)
+		return b.Delete(k)
+	})
+	return request, err
+}
+
+func (s *BoltQueueStorage) QueueSize() (int, error) {
+	size := 0
+	err := s.db.View(func(tx *bolt.Tx) error {
+		size = tx.Bucket(queueBucket).Stats().KeyN
+		return nil
+	})
+	return size, err
+}
+
+func itob(id uint64) []byte {
+	b := make([]byte, 8)
+	for i := 7; i >= 0; i-- {
+		b[i] = byte(id & 0xff)
+		id >>= 8
+	}
+	return b
+}
+
+// newQueue builds the colly queue, backing it with BoltDB at statePath when
+// persistent is true so a killed crawl can pick back up where it left off.
+func newQueue(persistent bool, statePath string, threads int) (*queue.Queue, error) {
+	if persistent {
+		storage, err := NewBoltQueueStorage(statePath)
+		if err != nil {
+			return nil, err
+		}
+		return queue.New(threads, storage)
+	}
+	return queue.New(threads, &queue.InMemoryQueueStorage{MaxSize: 100000})
+}
+
+// PersistentSet is an on-disk, JSON-backed mapset.Set used by Precrawl so
+// `checked`/`allHyphae` survive a restart instead of resetting to empty.
+type PersistentSet struct {
+	path string
+	set  mapset.Set
+}
+
+// LoadPersistentSet reads path back into a Set of types.Hypha, the only
+// element type Precrawl stores in a PersistentSet. Unmarshaling into
+// []interface{} would decode each element as a map[string]interface{}
+// instead, which panics the moment it's hashed into the set.
+func LoadPersistentSet(path string) (*PersistentSet, error) {
+	set := mapset.NewSet()
+	if data, err := os.ReadFile(path); err == nil {
+		var items []types.Hypha
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			set.Add(item)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return &PersistentSet{path: path, set: set}, nil
+}
+
+func (p *PersistentSet) Save() error {
+	data, err := json.Marshal(p.set.ToSlice())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p.path, data, 0644)
+}
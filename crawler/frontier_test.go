@@ -0,0 +1,112 @@
+package crawler
+
+import (
+	"lieu/types"
+	"path/filepath"
+	"testing"
+
+	mapset "github.com/deckarep/golang-set"
+)
+
+func TestPersistentSetSaveLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hyphae.json")
+
+	set := mapset.NewSet()
+	set.Add(types.Hypha{Url: "https://a.example", Depth: 0})
+	set.Add(types.Hypha{Url: "https://b.example", Depth: 1})
+	saved := &PersistentSet{path: path, set: set}
+	if err := saved.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := LoadPersistentSet(path)
+	if err != nil {
+		t.Fatalf("LoadPersistentSet: %v", err)
+	}
+
+	if loaded.set.Cardinality() != 2 {
+		t.Fatalf("expected 2 entries, got %d", loaded.set.Cardinality())
+	}
+	if !loaded.set.Contains(types.Hypha{Url: "https://a.example", Depth: 0}) {
+		t.Fatalf("loaded set missing expected Hypha, got %v", loaded.set.ToSlice())
+	}
+}
+
+func TestLoadPersistentSetMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "does-not-exist.json")
+
+	loaded, err := LoadPersistentSet(path)
+	if err != nil {
+		t.Fatalf("LoadPersistentSet: %v", err)
+	}
+	if loaded.set.Cardinality() != 0 {
+		t.Fatalf("expected empty set for missing file, got %d entries", loaded.set.Cardinality())
+	}
+}
+
+func TestBoltQueueStorageAddGetRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.db")
+
+	storage, err := NewBoltQueueStorage(path)
+	if err != nil {
+		t.Fatalf("NewBoltQueueStorage: %v", err)
+	}
+	defer storage.Close()
+
+	if size, err := storage.QueueSize(); err != nil || size != 0 {
+		t.Fatalf("expected empty queue, got size=%d err=%v", size, err)
+	}
+
+	requests := [][]byte{[]byte("https://a.example"), []byte("https://b.example")}
+	for _, r := range requests {
+		if err := storage.AddRequest(r); err != nil {
+			t.Fatalf("AddRequest: %v", err)
+		}
+	}
+
+	if size, err := storage.QueueSize(); err != nil || size != len(requests) {
+		t.Fatalf("expected size=%d, got size=%d err=%v", len(requests), size, err)
+	}
+
+	// BoltQueueStorage is FIFO: GetRequest returns requests in the order
+	// they were added, driven by NextSequence's monotonically increasing
+	// big-endian keys.
+	for i, want := range requests {
+		got, err := storage.GetRequest()
+		if err != nil {
+			t.Fatalf("GetRequest: %v", err)
+		}
+		if string(got) != string(want) {
+			t.Fatalf("request %d: got %q, want %q", i, got, want)
+		}
+	}
+
+	if size, err := storage.QueueSize(); err != nil || size != 0 {
+		t.Fatalf("expected drained queue, got size=%d err=%v", size, err)
+	}
+}
+
+func TestBoltQueueStoragePersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.db")
+
+	storage, err := NewBoltQueueStorage(path)
+	if err != nil {
+		t.Fatalf("NewBoltQueueStorage: %v", err)
+	}
+	if err := storage.AddRequest([]byte("https://a.example")); err != nil {
+		t.Fatalf("AddRequest: %v", err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := NewBoltQueueStorage(path)
+	if err != nil {
+		t.Fatalf("NewBoltQueueStorage (reopen): %v", err)
+	}
+	defer reopened.Close()
+
+	if size, err := reopened.QueueSize(); err != nil || size != 1 {
+		t.Fatalf("expected the request to survive reopening, got size=%d err=%v", size, err)
+	}
+}
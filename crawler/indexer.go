@@ -0,0 +1,128 @@
+package crawler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"lieu/types"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// IndexRecord is a single piece of extracted page data, replacing the old
+// convention of printing ad-hoc "kind value url depth" lines to stdout.
+type IndexRecord struct {
+	Kind  string
+	URL   string
+	Depth int
+	Value string
+}
+
+// Indexer is anything that can accept a stream of IndexRecords produced
+// while crawling. The line-based indexer preserves the historical stdout
+// format so existing downstream tooling keeps working unchanged.
+type Indexer interface {
+	Emit(record IndexRecord) error
+	Close() error
+}
+
+// NewIndexer builds the Indexer configured for a crawl, defaulting to the
+// original stdout line format when config.Crawler.IndexFormat is unset.
+func NewIndexer(config types.Config) (Indexer, error) {
+	switch config.Crawler.IndexFormat {
+	case "", "line":
+		return &LineIndexer{}, nil
+	case "jsonl":
+		return NewJSONLIndexer(config.Crawler.IndexPath)
+	case "sqlite":
+		return NewSQLiteIndexer(config.Crawler.IndexPath)
+	default:
+		return nil, fmt.Errorf("unknown index format: %s", config.Crawler.IndexFormat)
+	}
+}
+
+// LineIndexer reproduces the original `fmt.Println(kind, value, url, depth)` output.
+type LineIndexer struct{}
+
+func (i *LineIndexer) Emit(record IndexRecord) error {
+	fmt.Println(record.Kind, record.Value, record.URL, record.Depth)
+	return nil
+}
+
+func (i *LineIndexer) Close() error {
+	return nil
+}
+
+// JSONLIndexer emits one JSON-encoded IndexRecord per line, either to stdout
+// (IndexPath == "" or "-") or to a file on disk.
+type JSONLIndexer struct {
+	out    *os.File
+	closer bool
+}
+
+func NewJSONLIndexer(path string) (*JSONLIndexer, error) {
+	if path == "" || path == "-" {
+		return &JSONLIndexer{out: os.Stdout}, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLIndexer{out: f, closer: true}, nil
+}
+
+func (i *JSONLIndexer) Emit(record IndexRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(i.out, string(line))
+	return err
+}
+
+func (i *JSONLIndexer) Close() error {
+	if i.closer {
+		return i.out.Close()
+	}
+	return nil
+}
+
+// SQLiteIndexer writes IndexRecords directly into a SQLite database, so a
+// search layer can query the crawl results without an intermediate file.
+type SQLiteIndexer struct {
+	db *sql.DB
+}
+
+func NewSQLiteIndexer(path string) (*SQLiteIndexer, error) {
+	if path == "" {
+		path = "index.db"
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS records (
+		kind TEXT,
+		url TEXT,
+		depth INTEGER,
+		value TEXT
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteIndexer{db: db}, nil
+}
+
+func (i *SQLiteIndexer) Emit(record IndexRecord) error {
+	_, err := i.db.Exec(
+		`INSERT INTO records (kind, url, depth, value) VALUES (?, ?, ?, ?)`,
+		record.Kind, record.URL, record.Depth, record.Value,
+	)
+	return err
+}
+
+func (i *SQLiteIndexer) Close() error {
+	return i.db.Close()
+}
@@ -0,0 +1,141 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"lieu/types"
+)
+
+func TestLineIndexerEmit(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = old }()
+
+	indexer := &LineIndexer{}
+	if err := indexer.Emit(IndexRecord{Kind: "desc", Value: "hello", URL: "https://example.com", Depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	w.Close()
+	os.Stdout = old
+	out, _ := io.ReadAll(r)
+	got := bytes.TrimRight(out, "\n")
+	want := "desc hello https://example.com 1"
+	if string(got) != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestJSONLIndexerEmitRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.jsonl")
+
+	indexer, err := NewJSONLIndexer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := IndexRecord{Kind: "keywords", Value: "go, crawler", URL: "https://example.com", Depth: 2}
+	if err := indexer.Emit(record); err != nil {
+		t.Fatal(err)
+	}
+	if err := indexer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		t.Fatal("expected one line in jsonl output")
+	}
+	var got IndexRecord
+	if err := json.Unmarshal(scanner.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != record {
+		t.Fatalf("got %+v, want %+v", got, record)
+	}
+}
+
+func TestSQLiteIndexerEmitRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.db")
+
+	indexer, err := NewSQLiteIndexer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := IndexRecord{Kind: "desc", Value: "a test page", URL: "https://example.com/page", Depth: 3}
+	if err := indexer.Emit(record); err != nil {
+		t.Fatal(err)
+	}
+	if err := indexer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var got IndexRecord
+	row := db.QueryRow(`SELECT kind, url, depth, value FROM records WHERE kind = ?`, record.Kind)
+	if err := row.Scan(&got.Kind, &got.URL, &got.Depth, &got.Value); err != nil {
+		t.Fatal(err)
+	}
+	if got != record {
+		t.Fatalf("got %+v, want %+v", got, record)
+	}
+}
+
+func TestNewIndexerDispatchesOnFormat(t *testing.T) {
+	dir := t.TempDir()
+
+	cases := []struct {
+		format string
+		want   interface{}
+	}{
+		{"", &LineIndexer{}},
+		{"line", &LineIndexer{}},
+		{"jsonl", &JSONLIndexer{}},
+		{"sqlite", &SQLiteIndexer{}},
+	}
+	for _, c := range cases {
+		config := types.Config{Crawler: types.CrawlerConfig{
+			IndexFormat: c.format,
+			IndexPath:   filepath.Join(dir, "out-"+c.format),
+		}}
+		indexer, err := NewIndexer(config)
+		if err != nil {
+			t.Fatalf("format %q: %v", c.format, err)
+		}
+		defer indexer.Close()
+
+		gotType := fmt.Sprintf("%T", indexer)
+		wantType := fmt.Sprintf("%T", c.want)
+		if gotType != wantType {
+			t.Errorf("format %q: got %s, want %s", c.format, gotType, wantType)
+		}
+	}
+
+	if _, err := NewIndexer(types.Config{Crawler: types.CrawlerConfig{IndexFormat: "csv"}}); err == nil {
+		t.Fatal("expected error for unknown index format")
+	}
+}
@@ -0,0 +1,44 @@
+package crawler
+
+import (
+	"net"
+	"strings"
+
+	"github.com/abadojack/whatlanggo"
+	"golang.org/x/net/publicsuffix"
+)
+
+// isGenericLang reports whether a declared html[lang] value is missing or
+// too generic to trust, and so should be backfilled by detection.
+func isGenericLang(lang string) bool {
+	switch strings.ToLower(lang) {
+	case "", "en", "und":
+		return true
+	}
+	return false
+}
+
+// detectLanguage guesses the ISO-639-1 code for text, returning "" when
+// whatlanggo isn't confident enough to bother emitting a record.
+func detectLanguage(text string) string {
+	info := whatlanggo.Detect(text)
+	if info.Lang < 0 || !info.IsReliable() {
+		return ""
+	}
+	return info.Lang.Iso6391()
+}
+
+// registeredDomain returns the eTLD+1 for hostname, falling back to the
+// hostname itself when publicsuffix can't parse it (e.g. bare IPs).
+func registeredDomain(hostname string) string {
+	// publicsuffix treats an IP's last octets as labels ("127.0.0.1" -> "0.1")
+	// rather than failing, so IPs are caught here.
+	if net.ParseIP(hostname) != nil {
+		return hostname
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(hostname)
+	if err != nil {
+		return hostname
+	}
+	return domain
+}
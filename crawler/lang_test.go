@@ -0,0 +1,32 @@
+package crawler
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	// whatlanggo.Detect returns Lang == -1 (not a Und constant) when it
+	// can't identify a script at all.
+	if detected := detectLanguage(""); detected != "" {
+		t.Fatalf("expected no detection for empty text, got %q", detected)
+	}
+
+	text := "Dies ist ein deutscher Beispieltext, der lang genug ist, um zuverlässig erkannt zu werden."
+	if detected := detectLanguage(text); detected != "de" {
+		t.Fatalf("expected German text to detect as \"de\", got %q", detected)
+	}
+}
+
+func TestIsGenericLang(t *testing.T) {
+	cases := map[string]bool{
+		"":    true,
+		"en":  true,
+		"EN":  true,
+		"und": true,
+		"de":  false,
+		"fr":  false,
+	}
+	for lang, want := range cases {
+		if got := isGenericLang(lang); got != want {
+			t.Errorf("isGenericLang(%q) = %v, want %v", lang, got, want)
+		}
+	}
+}
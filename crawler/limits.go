@@ -0,0 +1,112 @@
+package crawler
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gocolly/colly/v2"
+	"golang.org/x/net/publicsuffix"
+
+	mapset "github.com/deckarep/golang-set"
+)
+
+// hostLimiter enforces the two smart-crawling caps that keep a single
+// shared domain (blogspot.com, tilde hosts, ...) from dominating the
+// frontier: a cap on distinct hostnames crawled per registered domain, and
+// a cap on pages crawled per host. allowHost/allowPage are called from
+// colly's OnHTML/OnRequest callbacks, which run concurrently (queue
+// threads, per-domain Parallelism), so both maps are guarded by a mutex.
+type hostLimiter struct {
+	maxHostsPerDomain int
+	maxPagesPerHost   int
+
+	mu            sync.Mutex
+	hostsByDomain map[string]mapset.Set
+	pagesByHost   map[string]int
+}
+
+func newHostLimiter(maxHostsPerDomain, maxPagesPerHost int) *hostLimiter {
+	return &hostLimiter{
+		maxHostsPerDomain: maxHostsPerDomain,
+		maxPagesPerHost:   maxPagesPerHost,
+		hostsByDomain:     make(map[string]mapset.Set),
+		pagesByHost:       make(map[string]int),
+	}
+}
+
+// allowHost reports whether a new hostname may be added to the frontier,
+// tracking it against its registered domain's cap as a side effect.
+func (h *hostLimiter) allowHost(hostname string) bool {
+	if h.maxHostsPerDomain <= 0 {
+		return true
+	}
+
+	domain, err := publicsuffix.EffectiveTLDPlusOne(hostname)
+	if err != nil {
+		domain = hostname
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hosts, ok := h.hostsByDomain[domain]
+	if !ok {
+		hosts = mapset.NewSet()
+		h.hostsByDomain[domain] = hosts
+	}
+	if hosts.Contains(hostname) {
+		return true
+	}
+	if hosts.Cardinality() >= h.maxHostsPerDomain {
+		return false
+	}
+	hosts.Add(hostname)
+	return true
+}
+
+// allowPage reports whether another page may be crawled on hostname,
+// incrementing its counter as a side effect.
+func (h *hostLimiter) allowPage(hostname string) bool {
+	if h.maxPagesPerHost <= 0 {
+		return true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.pagesByHost[hostname]++
+	return h.pagesByHost[hostname] <= h.maxPagesPerHost
+}
+
+// setupHostLimits registers an OnRequest preflight that enforces
+// MaxPagesPerHost and skips responses that aren't HTML or are too large,
+// via a HEAD request before colly fetches the body.
+func setupHostLimits(c *colly.Collector, limiter *hostLimiter, maxBodyBytes int64) {
+	c.OnRequest(func(r *colly.Request) {
+		if !limiter.allowPage(r.URL.Hostname()) {
+			r.Abort()
+			return
+		}
+
+		resp, err := http.Head(r.URL.String())
+		if err != nil {
+			// if HEAD fails outright, let colly's normal GET surface the error
+			return
+		}
+		defer resp.Body.Close()
+
+		contentType := resp.Header.Get("Content-Type")
+		if contentType != "" && !isHTMLContentType(contentType) {
+			r.Abort()
+			return
+		}
+		if maxBodyBytes > 0 && resp.ContentLength > maxBodyBytes {
+			r.Abort()
+		}
+	})
+}
+
+func isHTMLContentType(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/html") || strings.HasPrefix(contentType, "application/xhtml+xml")
+}
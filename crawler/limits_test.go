@@ -0,0 +1,57 @@
+package crawler
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// allowHost/allowPage are written from colly's OnHTML/OnRequest callbacks,
+// which run concurrently across hosts; run with -race to catch regressions.
+func TestHostLimiterConcurrentAccess(t *testing.T) {
+	limiter := newHostLimiter(2, 3)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		host := fmt.Sprintf("host%d.example.com", i%5)
+		wg.Add(1)
+		go func(h string) {
+			defer wg.Done()
+			limiter.allowHost(h)
+			limiter.allowPage(h)
+		}(host)
+	}
+	wg.Wait()
+}
+
+func TestHostLimiterAllowHostCapsPerDomain(t *testing.T) {
+	limiter := newHostLimiter(2, 0)
+
+	if !limiter.allowHost("a.example.com") {
+		t.Fatal("expected first host to be allowed")
+	}
+	if !limiter.allowHost("b.example.com") {
+		t.Fatal("expected second host to be allowed")
+	}
+	if limiter.allowHost("c.example.com") {
+		t.Fatal("expected third host on the same domain to be rejected")
+	}
+	// a previously-allowed host stays allowed even once the cap is hit
+	if !limiter.allowHost("a.example.com") {
+		t.Fatal("expected an already-allowed host to remain allowed")
+	}
+}
+
+func TestHostLimiterAllowPageCapsPerHost(t *testing.T) {
+	limiter := newHostLimiter(0, 2)
+
+	if !limiter.allowPage("a.example.com") {
+		t.Fatal("expected first page to be allowed")
+	}
+	if !limiter.allowPage("a.example.com") {
+		t.Fatal("expected second page to be allowed")
+	}
+	if limiter.allowPage("a.example.com") {
+		t.Fatal("expected third page on the same host to be rejected")
+	}
+}
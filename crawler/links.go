@@ -0,0 +1,127 @@
+package crawler
+
+import (
+	"net/url"
+	"regexp"
+
+	mapset "github.com/deckarep/golang-set"
+	"github.com/gocolly/colly/v2"
+	"github.com/gocolly/colly/v2/queue"
+)
+
+// LinkTag distinguishes the links we actually care about indexing (Primary,
+// the <a href> webring graph) from the assets a page merely depends on
+// (Related: images, scripts, stylesheets) so archive mode can treat them
+// differently.
+type LinkTag int
+
+const (
+	Primary LinkTag = iota
+	Related
+)
+
+var cssURLPattern = regexp.MustCompile(`(?:@import|:).*url\(["']?([^'"\)]+)["']?\)`)
+
+// crossDomainTracker remembers which hostnames ArchiveMode has allowed past
+// the webring's domain boundary for asset fetching. Crawl enforces this
+// itself via an OnRequest check rather than growing colly's own
+// c.AllowedDomains at runtime, since colly reads that slice unguarded from
+// its own internal goroutines - mapset.Set is safe for this kind of
+// concurrent Contains/Add from callbacks, a plain slice append is not.
+type crossDomainTracker struct {
+	hosts mapset.Set
+}
+
+func newCrossDomainTracker() *crossDomainTracker {
+	return &crossDomainTracker{hosts: mapset.NewSet()}
+}
+
+// crossed reports whether host was let in purely to fetch an asset, i.e. it
+// isn't one of the webring's own domains.
+func (t *crossDomainTracker) crossed(host string) bool {
+	return t.hosts.Contains(host)
+}
+
+// allow records host as crossed into the first time ArchiveMode lets an
+// asset link reach it.
+func (t *crossDomainTracker) allow(host string) {
+	t.hosts.Add(host)
+}
+
+// domainAllowed reports whether host is one of the webring's own domains or
+// has been crossed into for an asset fetch. Crawl's OnRequest handler uses
+// this in place of colly's own c.AllowedDomains filtering, since that
+// filtering can't grow at runtime without racing colly's internal reads of
+// the slice.
+func domainAllowed(domains []string, tracker *crossDomainTracker, host string) bool {
+	return find(domains, host) || tracker.crossed(host)
+}
+
+// queueLink enqueues link for crawling. Related (asset) links are allowed to
+// cross a domain boundary, when ArchiveMode is on, so an operator can pull
+// down a fuller offline mirror instead of just the index graph. That
+// crossing is scoped to one hop: Primary (<a href>) links are never
+// followed onto, or away from, a host that was only let in for assets.
+// Enqueued assets still go through setupHostLimits' HEAD preflight, which
+// aborts any request whose Content-Type is set and is not HTML, so
+// stylesheets, scripts and images served with their usual types are not
+// fetched.
+func queueLink(q *queue.Queue, link string, tag LinkTag, domains []string, currentDomain, outgoingDomain string, archiveMode bool, tracker *crossDomainTracker) {
+	if tag == Primary {
+		if !find(domains, outgoingDomain) && tracker.crossed(outgoingDomain) {
+			return
+		}
+		if !find(domains, currentDomain) && tracker.crossed(currentDomain) {
+			return
+		}
+		q.AddURL(link)
+		return
+	}
+
+	if find(domains, outgoingDomain) {
+		q.AddURL(link)
+		return
+	}
+
+	if !archiveMode {
+		return
+	}
+
+	tracker.allow(outgoingDomain)
+	q.AddURL(link)
+}
+
+// handleAssetLinks registers the Related-tagged asset handlers: stylesheet
+// and script references, image sources, and CSS @import/url(...) references
+// inside inline <style> blocks.
+func handleAssetLinks(c *colly.Collector, q *queue.Queue, domains []string, archiveMode bool, tracker *crossDomainTracker) {
+	enqueueAsset := func(e *colly.HTMLElement, raw string) {
+		if raw == "" {
+			return
+		}
+		link := e.Request.AbsoluteURL(getLink(raw))
+		u, err := url.Parse(link)
+		if err != nil {
+			return
+		}
+		queueLink(q, link, Related, domains, e.Request.URL.Hostname(), u.Hostname(), archiveMode, tracker)
+	}
+
+	c.OnHTML("link[href]", func(e *colly.HTMLElement) {
+		enqueueAsset(e, e.Attr("href"))
+	})
+
+	c.OnHTML("img[src]", func(e *colly.HTMLElement) {
+		enqueueAsset(e, e.Attr("src"))
+	})
+
+	c.OnHTML("script[src]", func(e *colly.HTMLElement) {
+		enqueueAsset(e, e.Attr("src"))
+	})
+
+	c.OnHTML("style", func(e *colly.HTMLElement) {
+		for _, match := range cssURLPattern.FindAllStringSubmatch(e.Text, -1) {
+			enqueueAsset(e, match[1])
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package crawler
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/gocolly/colly/v2/queue"
+)
+
+func newTestQueue(t *testing.T) *queue.Queue {
+	t.Helper()
+	q, err := queue.New(1, &queue.InMemoryQueueStorage{MaxSize: 100})
+	if err != nil {
+		t.Fatalf("queue.New: %v", err)
+	}
+	return q
+}
+
+func TestQueueLinkRelatedCrossesDomainOnce(t *testing.T) {
+	q := newTestQueue(t)
+	tracker := newCrossDomainTracker()
+	domains := []string{"webring.example"}
+
+	queueLink(q, "https://cdn.example/style.css", Related, domains, "webring.example", "cdn.example", true, tracker)
+
+	if !tracker.crossed("cdn.example") {
+		t.Fatal("expected cdn.example to be tracked as crossed")
+	}
+}
+
+func TestQueueLinkPrimaryDoesNotFollowPastCrossedHost(t *testing.T) {
+	q := newTestQueue(t)
+	tracker := newCrossDomainTracker()
+	domains := []string{"webring.example"}
+
+	// the asset crossing happens first, as it would on a real crawl
+	queueLink(q, "https://cdn.example/style.css", Related, domains, "webring.example", "cdn.example", true, tracker)
+
+	sizeBefore, err := q.Size()
+	if err != nil {
+		t.Fatalf("Size: %v", err)
+	}
+
+	// an <a href> discovered on a page hosted at the crossed asset domain
+	// must not be followed - crossing is scoped to one hop.
+	queueLink(q, "https://cdn.example/other-page", Primary, domains, "cdn.example", "cdn.example", true, tracker)
+
+	sizeAfter, err := q.Size()
+	if err != nil {
+		t.Fatalf("Size: %v", err)
+	}
+	if sizeAfter != sizeBefore {
+		t.Fatalf("expected primary link from a crossed host not to be enqueued, queue grew from %d to %d", sizeBefore, sizeAfter)
+	}
+
+	// likewise, an <a href> on a webring page pointing at the crossed
+	// asset domain must not be followed.
+	queueLink(q, "https://cdn.example/landing", Primary, domains, "webring.example", "cdn.example", true, tracker)
+
+	sizeFinal, err := q.Size()
+	if err != nil {
+		t.Fatalf("Size: %v", err)
+	}
+	if sizeFinal != sizeBefore {
+		t.Fatalf("expected primary link onto a crossed host not to be enqueued, queue grew from %d to %d", sizeBefore, sizeFinal)
+	}
+}
+
+func TestDomainAllowed(t *testing.T) {
+	tracker := newCrossDomainTracker()
+	domains := []string{"webring.example"}
+
+	if !domainAllowed(domains, tracker, "webring.example") {
+		t.Fatal("expected a webring domain to be allowed")
+	}
+	if domainAllowed(domains, tracker, "cdn.example") {
+		t.Fatal("expected an un-crossed domain to be disallowed")
+	}
+
+	tracker.allow("cdn.example")
+	if !domainAllowed(domains, tracker, "cdn.example") {
+		t.Fatal("expected a crossed domain to become allowed")
+	}
+}
+
+// domainAllowed is what Crawl's OnRequest handler calls instead of relying
+// on colly's own c.AllowedDomains, since colly reads that slice unguarded
+// from its own internal goroutines - appending to it from a callback, even
+// under a private mutex, can't make colly's side of the read race-free.
+// tracker.allow and domainAllowed are both backed by mapset.Set, so they're
+// safe to call concurrently the way a real crawl would.
+func TestDomainAllowedConcurrentAccess(t *testing.T) {
+	tracker := newCrossDomainTracker()
+	domains := []string{"webring.example"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		host := fmt.Sprintf("cdn%d.example", i%5)
+		wg.Add(1)
+		go func(h string) {
+			defer wg.Done()
+			domainAllowed(domains, tracker, h)
+			tracker.allow(h)
+			domainAllowed(domains, tracker, h)
+		}(host)
+	}
+	wg.Wait()
+}
+
+func TestQueueLinkPrimaryFollowsWebringDomains(t *testing.T) {
+	q := newTestQueue(t)
+	tracker := newCrossDomainTracker()
+	domains := []string{"webring.example"}
+
+	queueLink(q, "https://webring.example/page2", Primary, domains, "webring.example", "webring.example", true, tracker)
+
+	size, err := q.Size()
+	if err != nil {
+		t.Fatalf("Size: %v", err)
+	}
+	if size != 1 {
+		t.Fatalf("expected the primary webring link to be enqueued, queue size is %d", size)
+	}
+}
@@ -0,0 +1,127 @@
+package crawler
+
+import (
+	"encoding/xml"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/gocolly/colly/v2/queue"
+	"github.com/temoto/robotstxt"
+)
+
+// robotsCache fetches and caches each host's robots.txt so it's only
+// requested once per crawl, and lets us honor Crawl-Delay (which colly
+// doesn't expose on its own) and discover sitemaps. Colly runs OnRequest
+// callbacks concurrently (per-domain Parallelism), so access is guarded by
+// a mutex.
+type robotsCache struct {
+	mu   sync.Mutex
+	data map[string]*robotstxt.RobotsData
+}
+
+func newRobotsCache() *robotsCache {
+	return &robotsCache{data: make(map[string]*robotstxt.RobotsData)}
+}
+
+// get fetches and caches scheme://host/robots.txt, using scheme from the
+// request that triggered the lookup rather than assuming https, so
+// http-only sites get Crawl-Delay/sitemap handling too.
+func (rc *robotsCache) get(scheme, host string) *robotstxt.RobotsData {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if data, ok := rc.data[host]; ok {
+		return data
+	}
+
+	resp, err := http.Get((&url.URL{Scheme: scheme, Host: host, Path: "/robots.txt"}).String())
+	if err != nil {
+		rc.data[host] = nil
+		return nil
+	}
+	defer resp.Body.Close()
+
+	data, err := robotstxt.FromResponse(resp)
+	if err != nil {
+		data = nil
+	}
+	rc.data[host] = data
+	return data
+}
+
+type sitemapURLSet struct {
+	URLs []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+}
+
+func enqueueSitemap(q *queue.Queue, sitemapURL string) {
+	resp, err := http.Get(sitemapURL)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	var urlset sitemapURLSet
+	if err := xml.NewDecoder(resp.Body).Decode(&urlset); err != nil {
+		return
+	}
+	for _, u := range urlset.URLs {
+		q.AddURL(u.Loc)
+	}
+}
+
+// setupRobots wires robots.txt handling into the collector. It fetches and
+// enforces User-agent "moldy" (falling back to "*") rules itself via
+// robotstxt.TestAgent, rather than leaning on colly's own c.IgnoreRobotsTxt
+// support, which would fetch robots.txt a second time per host; per-host
+// Crawl-Delay is applied as a LimitRule, and, when useSitemaps is set, any
+// Sitemap: directives are enqueued. Colly invokes OnRequest concurrently
+// across hosts (queue threads, per-domain Parallelism), so the seen set is
+// guarded by a mutex.
+func setupRobots(c *colly.Collector, q *queue.Queue, rc *robotsCache, defaultDelay time.Duration, useSitemaps bool) {
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+
+	c.OnRequest(func(r *colly.Request) {
+		host := r.URL.Hostname()
+
+		mu.Lock()
+		alreadySeen := seen[host]
+		seen[host] = true
+		mu.Unlock()
+
+		data := rc.get(r.URL.Scheme, host)
+		if data == nil {
+			return
+		}
+
+		if !data.TestAgent(r.URL.Path, "moldy") {
+			r.Abort()
+			return
+		}
+
+		if alreadySeen {
+			return
+		}
+
+		group := data.FindGroup("moldy")
+		delay := defaultDelay
+		if group.CrawlDelay > 0 {
+			delay = group.CrawlDelay
+		}
+		if err := c.Limit(&colly.LimitRule{DomainGlob: "*" + host, Delay: delay, Parallelism: 3}); err != nil {
+			log.Println("failed to apply crawl-delay limit for", host, err)
+		}
+
+		if useSitemaps {
+			for _, sitemap := range data.Sitemaps {
+				enqueueSitemap(q, sitemap)
+			}
+		}
+	})
+}
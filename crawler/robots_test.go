@@ -0,0 +1,62 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func newRobotsTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "User-agent: *\nDisallow: /private")
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// httptest.Server only serves http, so using the request's own scheme
+// (rather than a hardcoded "https") is required for this to succeed.
+func TestRobotsCacheGetUsesRequestScheme(t *testing.T) {
+	srv := newRobotsTestServer(t)
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+
+	rc := newRobotsCache()
+	data := rc.get(u.Scheme, u.Host)
+	if data == nil {
+		t.Fatal("expected robots data, got nil")
+	}
+	if data.TestAgent("/private", "moldy") {
+		t.Fatal("expected /private to be disallowed")
+	}
+	if !data.TestAgent("/public", "moldy") {
+		t.Fatal("expected /public to be allowed")
+	}
+}
+
+// rc.data is written from colly's OnRequest callback, which runs
+// concurrently across hosts; run with -race to catch regressions.
+func TestRobotsCacheConcurrentAccess(t *testing.T) {
+	srv := newRobotsTestServer(t)
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+
+	rc := newRobotsCache()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rc.get(u.Scheme, u.Host)
+		}()
+	}
+	wg.Wait()
+}
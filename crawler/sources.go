@@ -0,0 +1,114 @@
+package crawler
+
+import (
+	"bufio"
+	"encoding/xml"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"lieu/types"
+	"lieu/util"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// precrawlHTML treats config.General.URL as a hand-maintained HTML
+// directory page, pulling links out via a configurable CSS selector so any
+// webring with a plain link list works without a bespoke API.
+func precrawlHTML(config types.Config) {
+	myClient := &http.Client{Timeout: 10 * time.Second}
+	res, err := myClient.Get(config.General.URL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	doc, err := goquery.NewDocumentFromReader(res.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	base, err := url.Parse(config.General.URL)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	selector := config.General.WebringSelector
+	if selector == "" {
+		selector = "li > a[href]:first-of-type"
+	}
+
+	BANNED := getBannedDomains(config.Crawler.BannedDomains)
+	util.QuerySelector(doc, selector).Each(func(i int, s *goquery.Selection) {
+		href, ok := s.Attr("href")
+		if !ok {
+			return
+		}
+		resolved, err := base.Parse(href)
+		if err != nil {
+			return
+		}
+		printWebringLink(resolved.String(), 0, BANNED)
+	})
+}
+
+type opmlDocument struct {
+	Body struct {
+		Outlines []struct {
+			XMLURL  string `xml:"xmlUrl,attr"`
+			HTMLURL string `xml:"htmlUrl,attr"`
+		} `xml:"outline"`
+	} `xml:"body"`
+}
+
+// precrawlOPML treats config.General.URL as an OPML feed list, the format
+// most podcast/RSS directories already export.
+func precrawlOPML(config types.Config) {
+	myClient := &http.Client{Timeout: 10 * time.Second}
+	res, err := myClient.Get(config.General.URL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	var doc opmlDocument
+	if err := xml.NewDecoder(res.Body).Decode(&doc); err != nil {
+		log.Fatal(err)
+	}
+
+	BANNED := getBannedDomains(config.Crawler.BannedDomains)
+	for _, outline := range doc.Body.Outlines {
+		link := outline.HTMLURL
+		if link == "" {
+			link = outline.XMLURL
+		}
+		if link == "" {
+			continue
+		}
+		printWebringLink(link, 0, BANNED)
+	}
+}
+
+// precrawlList treats config.General.URL as a plain newline-delimited list
+// of webring member URLs.
+func precrawlList(config types.Config) {
+	myClient := &http.Client{Timeout: 10 * time.Second}
+	res, err := myClient.Get(config.General.URL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	BANNED := getBannedDomains(config.Crawler.BannedDomains)
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		printWebringLink(line, 0, BANNED)
+	}
+}
@@ -0,0 +1,108 @@
+package crawler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"lieu/types"
+)
+
+// capturePrecrawlOutput runs fn with os.Stdout redirected, returning
+// whatever it printed. precrawlHTML/OPML/List all print via
+// printWebringLink, so this is the only way to observe their output.
+func capturePrecrawlOutput(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stdout
+	os.Stdout = w
+
+	fn()
+
+	w.Close()
+	os.Stdout = old
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+func TestPrecrawlHTMLResolvesRelativeLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `<html><body><ul>
+			<li><a href="/~alice">alice</a></li>
+			<li><a href="https://bob.example/">bob</a></li>
+		</ul></body></html>`)
+	}))
+	defer server.Close()
+
+	config := types.Config{General: types.GeneralConfig{URL: server.URL}}
+
+	out := capturePrecrawlOutput(t, func() { precrawlHTML(config) })
+
+	if !strings.Contains(out, server.URL+"/~alice") {
+		t.Fatalf("expected root-relative href resolved against the server origin, got %q", out)
+	}
+	if !strings.Contains(out, "https://bob.example") {
+		t.Fatalf("expected absolute href to pass through unchanged, got %q", out)
+	}
+}
+
+func TestPrecrawlHTMLPrintsLinksWhenNothingBanned(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `<ul><li><a href="https://banned.example/">nope</a></li></ul>`)
+	}))
+	defer server.Close()
+
+	config := types.Config{General: types.GeneralConfig{URL: server.URL}}
+	config.Crawler.BannedDomains = ""
+
+	out := capturePrecrawlOutput(t, func() { precrawlHTML(config) })
+	if !strings.Contains(out, "banned.example") {
+		t.Fatalf("expected the link to be printed when nothing is banned, got %q", out)
+	}
+}
+
+func TestPrecrawlOPMLPrefersHTMLURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `<opml><body>
+			<outline xmlUrl="https://a.example/feed.xml" htmlUrl="https://a.example/"/>
+			<outline xmlUrl="https://b.example/feed.xml"/>
+		</body></opml>`)
+	}))
+	defer server.Close()
+
+	config := types.Config{General: types.GeneralConfig{URL: server.URL}}
+
+	out := capturePrecrawlOutput(t, func() { precrawlOPML(config) })
+
+	if !strings.Contains(out, "https://a.example 0") {
+		t.Fatalf("expected htmlUrl to be preferred over xmlUrl, got %q", out)
+	}
+	if !strings.Contains(out, "https://b.example/feed.xml") {
+		t.Fatalf("expected xmlUrl fallback when htmlUrl is absent, got %q", out)
+	}
+}
+
+func TestPrecrawlListPrintsEachLine(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "https://a.example/\n\nhttps://b.example/\n")
+	}))
+	defer server.Close()
+
+	config := types.Config{General: types.GeneralConfig{URL: server.URL}}
+
+	out := capturePrecrawlOutput(t, func() { precrawlList(config) })
+
+	if !strings.Contains(out, "https://a.example 0") {
+		t.Fatalf("expected first list entry, got %q", out)
+	}
+	if !strings.Contains(out, "https://b.example 0") {
+		t.Fatalf("expected second list entry, got %q", out)
+	}
+}
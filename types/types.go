@@ -0,0 +1,87 @@
+// Package types holds the shapes shared across lieu's crawler, indexer, and
+// CLI: the on-disk config format and the webring cluster/hypha/site records
+// that Precrawl walks.
+package types
+
+import "github.com/BurntSushi/toml"
+
+// Config is the parsed contents of config.toml. Paths (banned domains,
+// boring words, ...) point at newline-delimited list files that the crawler
+// loads with util.ReadList.
+type Config struct {
+	General GeneralConfig `toml:"general"`
+	Crawler CrawlerConfig `toml:"crawler"`
+	Data    DataConfig    `toml:"data"`
+}
+
+// GeneralConfig describes the site being crawled: where its webring lives,
+// in what format, and how to reach it.
+type GeneralConfig struct {
+	URL             string `toml:"url"`
+	Proxy           string `toml:"proxy"`
+	WebringFormat   string `toml:"webring-format"`
+	WebringSelector string `toml:"webring-selector"`
+}
+
+// CrawlerConfig holds the knobs that shape a crawl: persistence, rate
+// limiting, politeness, and the list files referenced by crawler.go's
+// getBannedDomains and friends.
+type CrawlerConfig struct {
+	Webring        string `toml:"webring"`
+	BannedDomains  string `toml:"banned-domains"`
+	BannedSuffixes string `toml:"banned-suffixes"`
+	BoringDomains  string `toml:"boring-domains"`
+	BoringWords    string `toml:"boring-words"`
+	PreviewQueries string `toml:"preview-queries"`
+
+	PersistentQueue bool   `toml:"persistent-queue"`
+	StatePath       string `toml:"state-path"`
+
+	IndexFormat string `toml:"index-format"`
+	IndexPath   string `toml:"index-path"`
+
+	RespectRobots  bool `toml:"respect-robots"`
+	DefaultDelayMs int  `toml:"default-delay-ms"`
+	UseSitemaps    bool `toml:"use-sitemaps"`
+
+	ArchiveMode bool `toml:"archive-mode"`
+
+	MaxHostsPerDomain int   `toml:"max-hosts-per-domain"`
+	MaxPagesPerHost   int   `toml:"max-pages-per-host"`
+	MaxBodyBytes      int64 `toml:"max-body-bytes"`
+}
+
+// DataConfig points at list files used while extracting page content.
+type DataConfig struct {
+	Heuristics string `toml:"heuristics"`
+}
+
+// LoadConfig reads and parses a config.toml at path.
+func LoadConfig(path string) (Config, error) {
+	var config Config
+	_, err := toml.DecodeFile(path, &config)
+	return config, err
+}
+
+// Cluster is the bespoke webring precrawl payload: the site being visited
+// (Location), the other hyphae (webring members) it points to, and the
+// spores (non-webring sites) it links out to.
+type Cluster struct {
+	Location string   `json:"location"`
+	Depth    int      `json:"-"`
+	Hyphae   []string `json:"hyphae"`
+	Spores   []string `json:"spores"`
+}
+
+// Hypha is a webring member URL discovered during Precrawl, tagged with the
+// depth it was found at.
+type Hypha struct {
+	Url   string `json:"url"`
+	Depth int    `json:"depth"`
+}
+
+// Site is a non-webring URL (a "spore") discovered during Precrawl.
+type Site struct {
+	Url   string `json:"url"`
+	Depth int    `json:"depth"`
+}
@@ -0,0 +1,60 @@
+// Package util holds small helpers shared across lieu's crawler and CLI
+// that don't belong to any one of them in particular.
+package util
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ReadList reads path and splits it on sep, dropping empty lines. It's used
+// to load the newline-delimited banned-domains/boring-words/... list files
+// referenced from config.toml. A missing path yields an empty list rather
+// than an error, since most of these lists are optional.
+func ReadList(path string, sep string) []string {
+	var list []string
+	if path == "" {
+		return list
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return list
+	}
+
+	for _, item := range strings.Split(string(data), sep) {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Check logs and exits on a non-nil error. It's used for failures that
+// should stop a crawl immediately rather than degrade gracefully.
+func Check(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Contains reports whether list holds item.
+func Contains(list []string, item string) bool {
+	for _, v := range list {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
+
+// QuerySelector runs a CSS selector against doc, accepting a comma
+// separated list of selectors the same way goquery.Selection.Find does, so
+// a webring directory page can be matched with more than one shape.
+func QuerySelector(doc *goquery.Document, selector string) *goquery.Selection {
+	return doc.Find(selector)
+}